@@ -0,0 +1,294 @@
+package equity
+
+import (
+	"math/rand"
+
+	"poker/comb"
+)
+
+// A PlayerInput describes what a Dealer knows about one player's hole
+// cards: concrete cards (Hole set), a distribution of possible hands
+// (Range set), or nothing at all if neither is set, in which case the
+// player is dealt uniformly from whatever's left in the deck.
+type PlayerInput struct {
+	Hole  []string
+	Range *Range
+}
+
+// NewConcreteHole returns a PlayerInput for a player whose hole cards are
+// known exactly.
+func NewConcreteHole(cards ...string) PlayerInput {
+	return PlayerInput{Hole: cards}
+}
+
+// NewRangeHole returns a PlayerInput for a player whose hole cards are
+// unknown but believed to come from r.
+func NewRangeHole(r *Range) PlayerInput {
+	return PlayerInput{Range: r}
+}
+
+// NewMaskedHole returns a PlayerInput for a player about whom nothing is
+// known; their hole cards are drawn uniformly from the undealt deck.
+func NewMaskedHole() PlayerInput {
+	return PlayerInput{}
+}
+
+// A Result is one player's equity, as a fraction of the pot. Scoop, Hi,
+// and Lo are only meaningful for Hi/Lo variants; in high-only variants Hi
+// always equals Win+Tie's pot share, Scoop equals Win, and Lo stays 0.
+type Result struct {
+	Win, Tie float64 // sole best hi hand, and tied-for-best hi hand
+	Scoop    float64 // took the entire pot alone
+	Hi, Lo   float64 // pot-equity share from the hi and lo sides
+}
+
+// A Dealer ties together a variant's Evaluator, the deck, and each
+// player's hole-card input across the remaining streets, so it can
+// compute multi-way equity for ranges as easily as for concrete hands.
+type Dealer struct {
+	ev      Evaluator
+	variant Type
+	players []PlayerInput
+	board   []uint32
+}
+
+// NewDealer returns a Dealer for variant, dealing out to players on top
+// of whatever of the board is already known. board may be partial (e.g.
+// just the flop) or empty; it is ignored for the stud-family variants,
+// which have no shared board.
+func NewDealer(variant Type, players []PlayerInput, board []string) *Dealer {
+	d := &Dealer{
+		ev:      NewEvaluator(variant),
+		variant: variant,
+		players: players,
+	}
+	if usesBoard(variant) {
+		d.board = cardsToInts(board)
+	}
+	return d
+}
+
+// holeLen is how many private cards each player holds in variant.
+func holeLen(t Type) int {
+	switch t {
+	case Omaha, OmahaHiLo, Badugi:
+		return 4
+	case Stud, StudHiLo, Razz:
+		return 7
+	default:
+		return 2
+	}
+}
+
+// usesBoard reports whether variant deals a shared board, as opposed to
+// the stud family, which deals every card privately.
+func usesBoard(t Type) bool {
+	switch t {
+	case Stud, StudHiLo, Razz, Badugi:
+		return false
+	default:
+		return true
+	}
+}
+
+// Equity returns each player's Result. trials is the number of
+// Monte-Carlo simulations to run; if trials is 0, every remaining combo
+// of hole cards and board run-out is enumerated exhaustively instead, so
+// each Range combo is naturally weighted by how many of its combos
+// survive the other players' and the board's cards. rng drives the
+// Monte-Carlo sampling, the same as in HandEquity.
+func (d *Dealer) Equity(trials int, rng *rand.Rand) []Result {
+	hLen := holeLen(d.variant)
+	boardTarget := 0
+	if usesBoard(d.variant) {
+		boardTarget = 5
+	}
+	boardNeed := boardTarget - len(d.board)
+
+	results := make([]Result, len(d.players))
+	var weight float64
+	if trials == 0 {
+		weight = d.exhaustive(hLen, boardNeed, results)
+	} else {
+		weight = float64(d.monteCarlo(trials, rng, hLen, boardNeed, results))
+	}
+	if weight == 0 {
+		// Nothing could be scored at all, e.g. every trial's ranges were
+		// exhausted by what was already dealt; leave results all zero
+		// rather than dividing by zero.
+		return results
+	}
+	for i := range results {
+		results[i].Win /= weight
+		results[i].Tie /= weight
+		results[i].Scoop /= weight
+		results[i].Hi /= weight
+		results[i].Lo /= weight
+	}
+	return results
+}
+
+// exhaustive assigns every player's hole cards and every board run-out in
+// turn, scoring each resulting combination, and returns the total number
+// of combinations scored.
+func (d *Dealer) exhaustive(hLen, boardNeed int, results []Result) float64 {
+	var weight float64
+	holes := make([][]uint32, len(d.players))
+	d.assignHoles(0, hLen, append([]uint32{}, d.board...), holes, func(used []uint32) {
+		if boardNeed == 0 {
+			weight++
+			scoreOne(d.ev, d.board, holes, results)
+			return
+		}
+		rest := NewDeck(used...)
+		full := make([]uint32, boardNeed)
+		c := comb.Generator(rest, uint32(boardNeed))
+		for more := true; more; {
+			more = c(full)
+			board := append(append([]uint32{}, d.board...), full...)
+			weight++
+			scoreOne(d.ev, board, holes, results)
+		}
+	})
+	return weight
+}
+
+// assignHoles recursively assigns hole cards to each player from idx on,
+// calling leaf once every player has been assigned. used accumulates the
+// cards spoken for so far, so each player's Range is weighted by however
+// many of its combos survive the players (and board) already assigned.
+func (d *Dealer) assignHoles(idx, hLen int, used []uint32, holes [][]uint32, leaf func(used []uint32)) {
+	if idx == len(d.players) {
+		leaf(used)
+		return
+	}
+	p := d.players[idx]
+	var combos [][]uint32
+	switch {
+	case len(p.Hole) > 0:
+		combos = [][]uint32{cardsToInts(p.Hole)}
+	case p.Range != nil:
+		combos = p.Range.Remove(used).Hands
+	default:
+		combos = comboSetFromDeck(NewDeck(used...), hLen)
+	}
+	for _, h := range combos {
+		holes[idx] = h
+		d.assignHoles(idx+1, hLen, append(append([]uint32{}, used...), h...), holes, leaf)
+	}
+}
+
+// monteCarlo runs trials independent deals, sampling each player's hole
+// cards (from their Range, or uniformly if masked) and the board run-out,
+// and scores each one. It returns how many trials were actually scored,
+// which can be less than trials: a trial is skipped, rather than scored,
+// if a player's Range has no combo left once the other players' and the
+// board's cards are removed.
+func (d *Dealer) monteCarlo(trials int, rng *rand.Rand, hLen, boardNeed int, results []Result) int {
+	holes := make([][]uint32, len(d.players))
+	scored := 0
+trial:
+	for t := 0; t < trials; t++ {
+		used := append([]uint32{}, d.board...)
+		for i, p := range d.players {
+			var h []uint32
+			switch {
+			case len(p.Hole) > 0:
+				h = cardsToInts(p.Hole)
+			case p.Range != nil:
+				combos := p.Range.Remove(used).Hands
+				if len(combos) == 0 {
+					continue trial
+				}
+				h = combos[rng.Intn(len(combos))]
+			default:
+				rest := NewDeck(used...)
+				shuffle(rng, rest)
+				h = append([]uint32{}, rest[:hLen]...)
+			}
+			holes[i] = h
+			used = append(used, h...)
+		}
+		board := append([]uint32{}, d.board...)
+		if boardNeed > 0 {
+			rest := NewDeck(used...)
+			shuffle(rng, rest)
+			board = append(board, rest[:boardNeed]...)
+		}
+		scoreOne(d.ev, board, holes, results)
+		scored++
+	}
+	return scored
+}
+
+// comboSetFromDeck returns every k-card combo out of deck.
+func comboSetFromDeck(deck []uint32, k int) [][]uint32 {
+	combos := make([][]uint32, 0)
+	hand := make([]uint32, k)
+	c := comb.Generator(deck, uint32(k))
+	for more := true; more; {
+		more = c(hand)
+		combos = append(combos, append([]uint32{}, hand...))
+	}
+	return combos
+}
+
+// scoreOne evaluates one board/hole combination for every player and adds
+// each player's win/tie/scoop/hi/lo counts for it into results.
+func scoreOne(ev Evaluator, board []uint32, holes [][]uint32, results []Result) {
+	his := make([]uint32, len(holes))
+	los := make([]uint32, len(holes))
+	haveLo := false
+	for i, h := range holes {
+		his[i], los[i] = ev.Eval(h, board)
+		if los[i] > 0 {
+			haveLo = true
+		}
+	}
+
+	hiMax, hiWinners := maxAndCount(his)
+	var loMax uint32
+	loWinners := 0
+	if haveLo {
+		loMax, loWinners = maxAndCount(los)
+	}
+	hiShare, loShare := 1.0, 0.0
+	if haveLo {
+		hiShare, loShare = 0.5, 0.5
+	}
+
+	for i := range holes {
+		isHiWinner := his[i] == hiMax
+		isLoWinner := haveLo && los[i] == loMax
+		if isHiWinner {
+			results[i].Hi += hiShare / float64(hiWinners)
+			if hiWinners == 1 {
+				results[i].Win++
+			} else {
+				results[i].Tie++
+			}
+		}
+		if isLoWinner {
+			results[i].Lo += loShare / float64(loWinners)
+		}
+		if isHiWinner && hiWinners == 1 && (!haveLo || (isLoWinner && loWinners == 1)) {
+			results[i].Scoop++
+		}
+	}
+}
+
+func maxAndCount(vals []uint32) (uint32, int) {
+	max := vals[0]
+	for _, v := range vals[1:] {
+		if v > max {
+			max = v
+		}
+	}
+	count := 0
+	for _, v := range vals {
+		if v == max {
+			count++
+		}
+	}
+	return max, count
+}
@@ -0,0 +1,47 @@
+package equity
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Three identical narrow ranges can exhaust every surviving combo between
+// them (e.g. only four Kings to go around for three KK ranges), which used
+// to panic on rng.Intn(0) inside monteCarlo. It should skip those trials
+// instead. Razz needs no hand-rank table, so this exercises the fix without
+// depending on one being loaded.
+func TestDealerMonteCarloSkipsExhaustedRange(t *testing.T) {
+	kk, err := ParseRange("KK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	players := []PlayerInput{NewRangeHole(kk), NewRangeHole(kk), NewRangeHole(kk)}
+	d := NewDealer(Razz, players, nil)
+	rng := rand.New(rand.NewSource(7))
+
+	res := d.Equity(500, rng)
+	for i, r := range res {
+		if math.IsNaN(r.Win) || math.IsNaN(r.Hi) {
+			t.Fatalf("player %d result has NaN: %+v", i, r)
+		}
+	}
+}
+
+// NewDealer documents that board is ignored for the stud-family variants,
+// which have no shared board. A caller who passes one anyway used to hit
+// a negative boardNeed and panic in exhaustive's make([]uint32, boardNeed);
+// it should just be dropped instead.
+func TestNewDealerIgnoresBoardForStudFamily(t *testing.T) {
+	players := []PlayerInput{
+		NewConcreteHole("2c", "3d", "4h", "5s", "6c", "7d", "8h"),
+		NewMaskedHole(),
+	}
+	d := NewDealer(Razz, players, []string{"2h", "3h"})
+	if len(d.board) != 0 {
+		t.Fatalf("NewDealer(Razz, ...) kept a board of %v, want it dropped", d.board)
+	}
+	// boardNeed used to go negative here (0 - 2) and panic making a
+	// negative-length slice; it should just run.
+	d.Equity(50, rand.New(rand.NewSource(3)))
+}
@@ -18,6 +18,14 @@
 //	QQ+,AQs+,AK       38  Any pair of Queen or better, any AQs, and any AK
 //	                      whether suited or not.
 //	AhKh,7h7d          2  Ace-King of Hearts or a pair of red Sevens.
+//
+// ParseRange turns a string in this syntax into a Range of concrete combos,
+// which HandEquity and HandEquity2 can take as the opponent's holdings.
+//
+// Hand evaluation needs a hand-rank table installed via SetDefaultTable
+// before it can do anything, except for Razz and Badugi, which rank hands
+// without one. LoadHandRanks loads a table from a file, which
+// GenerateHandRanks can write.
 package equity
 
 import (
@@ -25,8 +33,6 @@ import (
 	"fmt"
 	"math/big"
 	"math/rand"
-	"os"
-	"io"
 	"runtime"
 
 	"poker/comb"
@@ -37,31 +43,10 @@ const (
 	suits = "cdhs"
 )
 
-var hr [32487834]uint32
 var CTOI map[string]uint32
 var NCPU int // How many cpus to use for the equity calculations.
 
 func init() {
-	fmt.Print("Loading HandRanks.dat... ")
-	// Initialize hr
-	buf := make([]byte, len(hr)*4, len(hr)*4)
-	fp, err := os.Open("HandRanks.dat")
-	if err != nil {
-		panic(err)
-	}
-	defer fp.Close()
-	_, err = io.ReadFull(fp, buf)
-	if err != nil {
-		panic(err)
-	}
-	for i := 0; i < len(buf); i += 4 {
-		hr[i/4] = uint32(buf[i+3])<<24 |
-			uint32(buf[i+2])<<16 |
-			uint32(buf[i+1])<<8 |
-			uint32(buf[i])
-	}
-	fmt.Println("Done")
-
 	// Initialize CTOI
 	CTOI = make(map[string]uint32, 52)
 	var k uint32 = 1
@@ -73,8 +58,7 @@ func init() {
 	}
 
 	NCPU = runtime.NumCPU()
-	// FIXME: Increasing the number of CPUs slows the program down and makes the
-	// outcomes non-deterministic.
+	// FIXME: Increasing the number of CPUs slows the program down.
 	//runtime.GOMAXPROCS(NCPU)
 	//fmt.Printf("Using %d CPUs\n", NCPU)
 }
@@ -115,13 +99,21 @@ func (this *HandDist) Strs() [][]string {
 		ys[i] = string([]byte{this.Dist[1], suits[i]})
 	}
 	switch {
-	case len(this.Dist) == 2:
+	case len(this.Dist) == 2 && this.Dist[0] == this.Dist[1]:
 		// pairs e.g. AA
 		for i := 0; i < 3; i++ {
 			for j := i+1; j < 4; j++ {
 				hands = append(hands, []string{xs[i], xs[j]})
 			}
 		}
+	case len(this.Dist) == 2:
+		// any two ranks with no suited/offsuit qualifier, e.g. 54: every
+		// combo of the two ranks, suited and unsuited alike
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				hands = append(hands, []string{xs[i], ys[j]})
+			}
+		}
 	case this.Dist[2] == 'o':
 		// offsuit e.g. AKo
 		for i := 0; i < 4; i++ {
@@ -163,6 +155,7 @@ func NewRRSDist(r1, r2, suit int) *HandDist {
 }
 
 func evalBoard(cards []uint32) uint32 {
+	hr := table().hr
 	v := hr[53+cards[0]]
 	v = hr[v+cards[1]]
 	v = hr[v+cards[2]]
@@ -171,11 +164,13 @@ func evalBoard(cards []uint32) uint32 {
 }
 
 func evalHand(b uint32, cards []uint32) uint32 {
+	hr := table().hr
 	b = hr[b+cards[0]]
 	return hr[b+cards[1]]
 }
 
 func EvalHand(cards []string) uint32 {
+	hr := table().hr
 	hand := cardsToInts(cards)
 	v := hr[53+hand[0]]
 	v = hr[v+hand[1]]
@@ -192,46 +187,54 @@ func SplitRank(rank uint32) (uint32, uint32) {
 	return rank >> 12, rank & 0xFFF
 }
 
-// Calculate the percent of the pot each hand wins and return them as a slice.
-func evalHands(board []uint32, hands ...[]uint32) []float64 {
-	b := evalBoard(board)
-	// Optimize case where there are only two hands.
-	if len(hands) == 2 {
-		result := evalHand(b, hands[0]) - evalHand(b, hands[1])
-		switch {
-		case result > 0:
-			return []float64{1, 0}
-		case result < 0:
-			return []float64{0, 1}
-		default:
-			return []float64{0.5, 0.5}
+// Calculate the percent of the pot each hand wins under ev and return them
+// as a slice. If ev returns a qualifying lo rank for any hand, the pot is
+// split evenly between the hi half and the lo half, each alotted among
+// their own winners; otherwise the hi winners take it all.
+func evalHands(ev Evaluator, board []uint32, hands ...[]uint32) []float64 {
+	his := make([]uint32, len(hands), len(hands))
+	los := make([]uint32, len(hands), len(hands))
+	haveLo := false
+	for i, hand := range hands {
+		his[i], los[i] = ev.Eval(hand, board)
+		if los[i] > 0 {
+			haveLo = true
 		}
 	}
-	vals := make([]uint32, len(hands), len(hands))
-	for i, hand := range hands {
-		vals[i] = evalHand(b, hand)
+	result := make([]float64, len(hands), len(hands))
+	hiShare := 1.0
+	if haveLo {
+		hiShare = 0.5
+		awardShare(result, los, 0.5)
 	}
-	// Determine the number of winners and their hand.
-	winners := 1
+	awardShare(result, his, hiShare)
+	return result
+}
+
+// awardShare splits share evenly among the indices holding the maximum
+// value in vals and adds each winner's cut into result. A max of 0 means
+// nobody qualifies, e.g. an unqualified lo, so nothing is awarded.
+func awardShare(result []float64, vals []uint32, share float64) {
 	max := vals[0]
-	for i := 1; i < len(vals); i++ {
-		if v := vals[i]; v > max {
+	for _, v := range vals[1:] {
+		if v > max {
 			max = v
-			winners = 1
-		} else if v == max {
+		}
+	}
+	if max == 0 {
+		return
+	}
+	winners := 0
+	for _, v := range vals {
+		if v == max {
 			winners++
 		}
 	}
-	// Alot each winner his share of the pot.
-	result := make([]float64, len(hands), len(hands))
 	for i, v := range vals {
 		if v == max {
-			result[i] = 1.0 / float64(winners)
-		} else {
-			result[i] = 0.0
+			result[i] += share / float64(winners)
 		}
 	}
-	return result
 }
 
 // Calculate the probability of having a given class of hole cards.
@@ -312,8 +315,8 @@ func NewLottery(dist map[string] float64) *Lottery {
 
 // Draw a winner from a Lottery. If at least one value in the lottery is not >=
 // 1, then the greatest value is effectively rounded up to 1.0"
-func (this *Lottery) Play() string {
-	draw := rand.Float64()
+func (this *Lottery) Play(rng *rand.Rand) string {
+	draw := rng.Float64()
 	for i, p := range this.probs {
 		if p > draw {
 			return this.prizes[i]
@@ -360,63 +363,129 @@ func intersect(a, b [][]uint32) [][]uint32 {
 	return c[:count]
 }
 
-func shuffle(a []uint32) {
+func shuffle(rng *rand.Rand, a []uint32) {
 	for i := len(a) - 1; i > 0; i-- {
-		j := rand.Intn(i + 1)
+		j := rng.Intn(i + 1)
 		a[j], a[i] = a[i], a[j]
 	}
 }
 
+// DealN produces the canonical numbered deal for seed: a fixed-order
+// 52-card deck shuffled by repeatedly picking rng.Intn(remaining) and
+// swap-removing that card, FreeCell-style. The same seed always yields the
+// same deal, so a hand can be referenced and replayed by its integer id.
+func DealN(seed uint64) []uint32 {
+	rng := rand.New(rand.NewSource(int64(seed)))
+	deck := NewDeck()
+	dealt := make([]uint32, 0, len(deck))
+	for n := len(deck); n > 0; n-- {
+		i := rng.Intn(n)
+		dealt = append(dealt, deck[i])
+		deck[i] = deck[n-1]
+	}
+	return dealt
+}
+
 // HandEquity returns the equity of a player's hand based on the current
 // board.  trials is the number of Monte-Carlo simulations to do.  If trials
 // is 0, then exhaustive enumeration will be used instead.
-func HandEquity(sHand, sBoard []string, trials int, c chan float64) {
+//
+// ev determines how hole+board cards are scored, which also determines how
+// big a shared board is dealt out (0 for the stud family, which has none).
+// A random opponent is dealt as many hole cards as sHand holds.
+//
+// oppRange restricts the opponent to the combos in that Range, weighted by
+// however many of them remain after removing the hero's hole and board
+// cards. If oppRange is nil, the opponent is assumed to hold any of the
+// remaining cards at random, as before.
+//
+// rng drives the Monte-Carlo shuffles and opponent-combo draws, so the same
+// rng (or one seeded the same way) reproduces the same result.
+func HandEquity(ev Evaluator, sHand, sBoard []string, oppRange *Range, trials int, rng *rand.Rand, c chan float64) {
 	sum := 0.0
 	// Convert the cards from strings to ints.
 	hole := cardsToInts(sHand)
-	bLen := uint32(len(sBoard)) // How many cards will we need to draw?
-	board := make([]uint32, 5, 5)
+	oLen := uint32(len(hole))
+	bTarget := uint32(boardSizeFor(ev)) // how big a board does ev expect?
+	bLen := uint32(len(sBoard))         // how many cards will we need to draw?
+	board := make([]uint32, bTarget, bTarget)
 	for i, v := range sBoard {
 		board[i] = CTOI[v]
 	}
 
 	// Remove the hole and board cards from the deck.
-	deck := NewDeck(append(hole, board...)...)
+	seen := append(hole, board[:bLen]...)
+	deck := NewDeck(seen...)
+
+	var oppCombos [][]uint32
+	if oppRange != nil {
+		oppCombos = oppRange.Remove(seen).Hands
+		if len(oppCombos) == 0 {
+			c <- 0
+			return
+		}
+	}
 
 	if trials == 0 {
 		var count float64
 		// Exhaustive enumeration.
-		oHole := make([]uint32, 2, 2)
-		loop1, loop2 := true, true
-		c1 := comb.Generator(deck, 2)
-		for loop1 {
-			loop1 = c1(oHole)
-			c2 := comb.Generator(minus(deck, oHole), 5-bLen)
-			for loop2 {
-				loop2 = c2(board[bLen:])
-				sum += evalHands(board, hole, oHole)[0]
-				count++
+		if oppCombos != nil {
+			for _, oHole := range oppCombos {
+				loop2 := true
+				c2 := comb.Generator(minus(deck, oHole), bTarget-bLen)
+				for loop2 {
+					loop2 = c2(board[bLen:])
+					sum += evalHands(ev, board, hole, oHole)[0]
+					count++
+				}
+			}
+		} else {
+			oHole := make([]uint32, oLen, oLen)
+			loop1 := true
+			c1 := comb.Generator(deck, oLen)
+			for loop1 {
+				loop1 = c1(oHole)
+				loop2 := true
+				c2 := comb.Generator(minus(deck, oHole), bTarget-bLen)
+				for loop2 {
+					loop2 = c2(board[bLen:])
+					sum += evalHands(ev, board, hole, oHole)[0]
+					count++
+				}
 			}
 		}
 		c <- sum / count
 	} else {
 		// Monte-Carlo
 		for i := 0; i < trials; i++ {
-			shuffle(deck)
-			copy(board[bLen:], deck[2:8-bLen])
-			sum += evalHands(board, hole, deck[:2])[0]
+			var oHole []uint32
+			rest := deck
+			if oppCombos != nil {
+				oHole = oppCombos[rng.Intn(len(oppCombos))]
+				rest = minus(deck, oHole)
+				shuffle(rng, rest)
+				copy(board[bLen:], rest[:bTarget-bLen])
+			} else {
+				shuffle(rng, rest)
+				oHole = rest[:oLen]
+				copy(board[bLen:], rest[oLen:oLen+bTarget-bLen])
+			}
+			sum += evalHands(ev, board, hole, oHole)[0]
 		}
 		c <- sum / float64(trials)
 	}
 }
 
-// Parallel version of HandEquity.
-func HandEquity2(sHand, sBoard []string, trials int) float64 {
+// Parallel version of HandEquity. seed gives each of the NCPU goroutines
+// its own rand.Rand, seeded off of seed, so the result is reproducible for
+// a given seed and NCPU rather than depending on the process-global RNG.
+func HandEquity2(ev Evaluator, sHand, sBoard []string, oppRange *Range, trials int, seed int64) float64 {
 	sum := 0.0
 	trials += trials % NCPU // Round to a multiple of the number of CPUs.
     c := make(chan float64) // Not buffering
     for i := 0; i < NCPU; i++ {
-        go HandEquity(sHand, sBoard, trials/NCPU, c)
+        rng := rand.New(rand.NewSource(seed + int64(i)))
+        go HandEquity(ev, sHand, sBoard, oppRange, trials/NCPU, rng, c)
     }
     for i := 0; i < NCPU; i++ {
         sum += <-c
@@ -0,0 +1,281 @@
+package equity
+
+import (
+	"sort"
+
+	"poker/comb"
+)
+
+// Type identifies a poker variant understood by an Evaluator.
+type Type int
+
+const (
+	Holdem Type = iota
+	Omaha
+	OmahaHiLo
+	Stud
+	StudHiLo
+	Razz
+	Badugi
+)
+
+// An Evaluator scores a player's cards for one variant of poker. hole is
+// the player's private cards; board is the shared community cards, and is
+// empty for the stud-family variants, which fold every card into hole
+// instead. Eval returns the hi rank, and, for Hi/Lo variants, the best
+// qualifying lo rank. lo == 0 means the hand does not qualify for low.
+//
+// Both hi and lo ranks follow the bigger-is-better convention of the 2+2
+// hand-rank table, so evalHands can compare them the same way regardless
+// of variant.
+type Evaluator interface {
+	Eval(hole, board []uint32) (hi, lo uint32)
+}
+
+// NewEvaluator returns the Evaluator for the given variant.
+func NewEvaluator(t Type) Evaluator {
+	switch t {
+	case Holdem:
+		return holdemEval{}
+	case Omaha:
+		return omahaEval{}
+	case OmahaHiLo:
+		return omahaHiLoEval{}
+	case Stud:
+		return studEval{}
+	case StudHiLo:
+		return studHiLoEval{}
+	case Razz:
+		return razzEval{}
+	case Badugi:
+		return badugiEval{}
+	}
+	panic("equity: unknown variant")
+}
+
+// boardSizer is implemented by Evaluators that need callers to know how
+// many shared board cards they expect, so general-purpose callers like
+// HandEquity don't have to hardcode a variant's board size. Evaluators
+// that don't implement it are assumed to want the usual 5-card board.
+type boardSizer interface {
+	boardSize() int
+}
+
+// boardSizeFor returns how many shared board cards ev expects: 5 for the
+// board-dealing variants, 0 for the stud family, which folds every card
+// into hole instead.
+func boardSizeFor(ev Evaluator) int {
+	if bs, ok := ev.(boardSizer); ok {
+		return bs.boardSize()
+	}
+	return 5
+}
+
+// evalFive returns the rank of a 5-card hand. evalBoard stops on the
+// 5-card state of the hand-rank chain; the table keeps that hand's rank in
+// the state's 0th slot.
+func evalFive(cards []uint32) uint32 {
+	return table().hr[evalBoard(cards)]
+}
+
+// evalBest evaluates the best 5-card hand out of 5 to 7 cards using the
+// 2+2 hand-rank table.
+func evalBest(cards []uint32) uint32 {
+	if len(cards) == 5 {
+		return evalFive(cards)
+	}
+	var best uint32
+	c := comb.Generator(cards, 5)
+	five := make([]uint32, 5)
+	for more := true; more; {
+		more = c(five)
+		if r := evalFive(five); r > best {
+			best = r
+		}
+	}
+	return best
+}
+
+// bestLow returns the best (biggest) lowballRank over every 5-card subset
+// of cards, or 0 if none qualify.
+func bestLow(cards []uint32, eightOrBetter bool) uint32 {
+	if len(cards) < 5 {
+		return 0
+	}
+	var best uint32
+	c := comb.Generator(cards, 5)
+	five := make([]uint32, 5)
+	for more := true; more; {
+		more = c(five)
+		if r, ok := lowballRank(five, eightOrBetter); ok && r > best {
+			best = r
+		}
+	}
+	return best
+}
+
+// holdemEval is the 2-hole-card, best-of-7-with-the-board game, i.e. the
+// behavior evalHand/evalBoard already implemented.
+type holdemEval struct{}
+
+func (holdemEval) Eval(hole, board []uint32) (uint32, uint32) {
+	return evalHand(evalBoard(board), hole), 0
+}
+
+// omahaEval requires exactly 2 of the 4 hole cards and exactly 3 of the 5
+// board cards, so it tries all C(4,2)*C(5,3) = 60 combinations.
+type omahaEval struct{}
+
+func (omahaEval) Eval(hole, board []uint32) (uint32, uint32) {
+	var best uint32
+	eachOmahaCombo(hole, board, func(five []uint32) {
+		if r := evalFive(five); r > best {
+			best = r
+		}
+	})
+	return best, 0
+}
+
+type omahaHiLoEval struct{}
+
+func (omahaHiLoEval) Eval(hole, board []uint32) (uint32, uint32) {
+	var hi, lo uint32
+	eachOmahaCombo(hole, board, func(five []uint32) {
+		if r := evalFive(five); r > hi {
+			hi = r
+		}
+		if r, ok := lowballRank(five, true); ok && r > lo {
+			lo = r
+		}
+	})
+	return hi, lo
+}
+
+// eachOmahaCombo calls f with every 5-card hand formed from exactly 2 of
+// the 4 hole cards and exactly 3 of the 5 board cards.
+func eachOmahaCombo(hole, board []uint32, f func(five []uint32)) {
+	hc := comb.Generator(hole, 2)
+	h := make([]uint32, 2)
+	for hMore := true; hMore; {
+		hMore = hc(h)
+		bc := comb.Generator(board, 3)
+		b := make([]uint32, 3)
+		for bMore := true; bMore; {
+			bMore = bc(b)
+			f([]uint32{h[0], h[1], b[0], b[1], b[2]})
+		}
+	}
+}
+
+// studEval is best-5-of-7 from the player's hole and door cards, with no
+// shared board.
+type studEval struct{}
+
+func (studEval) Eval(hole, board []uint32) (uint32, uint32) {
+	cards := append(append([]uint32{}, hole...), board...)
+	if len(cards) < 5 {
+		return 0, 0
+	}
+	return evalBest(cards), 0
+}
+
+func (studEval) boardSize() int { return 0 }
+
+type studHiLoEval struct{}
+
+func (studHiLoEval) Eval(hole, board []uint32) (uint32, uint32) {
+	cards := append(append([]uint32{}, hole...), board...)
+	if len(cards) < 5 {
+		return 0, 0
+	}
+	return evalBest(cards), bestLow(cards, true)
+}
+
+func (studHiLoEval) boardSize() int { return 0 }
+
+// razzEval is ace-to-five lowball over the player's hole and door cards;
+// there are no straights or flushes to disqualify a hand.
+type razzEval struct{}
+
+func (razzEval) Eval(hole, board []uint32) (uint32, uint32) {
+	cards := append(append([]uint32{}, hole...), board...)
+	return bestLow(cards, false), 0
+}
+
+func (razzEval) boardSize() int { return 0 }
+
+// badugiEval picks the largest subset (up to 4 cards) of the player's
+// cards with all distinct ranks and all distinct suits, preferring lower
+// cards within a given size.
+type badugiEval struct{}
+
+func (badugiEval) Eval(hole, board []uint32) (uint32, uint32) {
+	cards := append(append([]uint32{}, hole...), board...)
+	return badugiRank(cards), 0
+}
+
+func (badugiEval) boardSize() int { return 0 }
+
+func badugiRank(cards []uint32) uint32 {
+	var bestSize int
+	var bestVals []int
+	n := len(cards)
+	for mask := 1; mask < 1<<uint(n); mask++ {
+		vals, ok := badugiSubset(cards, mask)
+		if !ok || len(vals) < bestSize {
+			continue
+		}
+		if len(vals) > bestSize || lessVals(vals, bestVals) {
+			bestSize, bestVals = len(vals), vals
+		}
+	}
+	score := 0
+	for _, v := range bestVals {
+		score = score*14 + v
+	}
+	return uint32(bestSize)*20000000 - uint32(score)
+}
+
+// badugiSubset reports the low-ball values, high to low, of the cards
+// selected by mask, provided they have distinct ranks and suits.
+func badugiSubset(cards []uint32, mask int) ([]int, bool) {
+	if bits := popcount(mask); bits > 4 {
+		return nil, false
+	}
+	ranksSeen := make(map[int]bool, 4)
+	suitsSeen := make(map[int]bool, 4)
+	var vals []int
+	for i, c := range cards {
+		if mask&(1<<uint(i)) == 0 {
+			continue
+		}
+		r, s := cardRank(c), cardSuit(c)
+		if ranksSeen[r] || suitsSeen[s] {
+			return nil, false
+		}
+		ranksSeen[r], suitsSeen[s] = true, true
+		vals = append(vals, lowValue(r))
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(vals)))
+	return vals, true
+}
+
+// lessVals reports whether a is a better (lower) badugi than b, card by
+// card from the top down. a and b must be the same length.
+func lessVals(a, b []int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+func popcount(mask int) int {
+	n := 0
+	for mask != 0 {
+		mask &= mask - 1
+		n++
+	}
+	return n
+}
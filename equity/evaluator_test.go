@@ -0,0 +1,19 @@
+package equity
+
+import "testing"
+
+func TestBadugiRankPrefersMoreDistinctCards(t *testing.T) {
+	fourCard := cardsToInts([]string{"2c", "3d", "4h", "5s"}) // four distinct ranks and suits
+	threeCard := cardsToInts([]string{"2c", "3d", "4h", "4s"}) // 4s pairs the 4h, so only 3 count
+	if badugiRank(fourCard) <= badugiRank(threeCard) {
+		t.Fatalf("a 4-card badugi should outrank a hand that can only make a 3-card badugi")
+	}
+}
+
+func TestBadugiRankLowerIsBetterWithinSize(t *testing.T) {
+	low := cardsToInts([]string{"2c", "3d", "4h", "5s"})
+	high := cardsToInts([]string{"2c", "3d", "4h", "9s"})
+	if badugiRank(low) <= badugiRank(high) {
+		t.Fatalf("a lower 4-card badugi should outrank a higher one")
+	}
+}
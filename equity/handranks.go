@@ -0,0 +1,442 @@
+// Loading and generating the 2+2-style hand-rank table that evalBoard,
+// evalHand, and EvalHand walk via a chain of hr[offset+card] lookups.
+package equity
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"unsafe"
+)
+
+// handRanksLen is the number of uint32 entries in a HandRanks.dat-shaped
+// table: enough branches for every reachable 1..6-card state plus the
+// terminal 7-card ranks.
+const handRanksLen = 32487834
+
+// A Table holds a 2+2-style hand-rank lookup table, the shared state that
+// evalBoard, evalHand, and EvalHand chain through. Use LoadHandRanks to
+// build one, from HandRanks.dat or from a file GenerateHandRanks wrote,
+// then SetDefaultTable to install it.
+type Table struct {
+	hr     []uint32
+	closer io.Closer
+}
+
+// Close releases any resources backing the table. It is safe to call on
+// a Table with nothing to release, which is always true of one produced
+// by LoadHandRanks.
+func (t *Table) Close() error {
+	if t.closer == nil {
+		return nil
+	}
+	return t.closer.Close()
+}
+
+var defaultTable *Table
+
+// SetDefaultTable installs t as the table used by evalBoard, evalHand,
+// EvalHand, and every Evaluator. Call it once at startup, typically with
+// the result of LoadHandRanks, before evaluating any hands.
+func SetDefaultTable(t *Table) {
+	defaultTable = t
+}
+
+func table() *Table {
+	if defaultTable == nil {
+		panic("equity: no hand-rank table loaded; call equity.SetDefaultTable first")
+	}
+	return defaultTable
+}
+
+// LoadHandRanks reads the 2+2 HandRanks.dat table at path. Rather than
+// decoding it one uint32 at a time, it reads the whole ~130MB file into a
+// single []byte and, on a little-endian host, reinterprets that buffer in
+// place as []uint32 with no further copy. Other hosts fall back to
+// decoding the bytes one uint32 at a time, as the old package-level
+// loader did.
+func LoadHandRanks(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("equity: opening %s: %v", path, err)
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("equity: reading %s: %v", path, err)
+	}
+	if len(b) != handRanksLen*4 {
+		return nil, fmt.Errorf("equity: %s is %d bytes, want %d", path, len(b), handRanksLen*4)
+	}
+	if isLittleEndian() {
+		return &Table{hr: bytesToUint32s(b)}, nil
+	}
+	hr := make([]uint32, handRanksLen)
+	for i := range hr {
+		hr[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+	return &Table{hr: hr}, nil
+}
+
+func bytesToUint32s(b []byte) []uint32 {
+	if len(b) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*uint32)(unsafe.Pointer(&b[0])), len(b)/4)
+}
+
+func isLittleEndian() bool {
+	var x uint16 = 1
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}
+
+// rootBranch is the fixed offset evalBoard, evalHand, and EvalHand all
+// start their hr[53+card] chain from; it comes from the original
+// HandRanks.dat layout and GenerateHandRanks preserves it so a generated
+// table is a drop-in replacement.
+const rootBranch = 53
+
+// branchSize is how many uint32 slots each branch reserves: one per card
+// value 1..52, plus the 0th slot, which holds the best 5-card rank of a 5-
+// or 6-card state and is unused otherwise.
+const branchSize = 53
+
+// GenerateHandRanks builds a hand-rank table from scratch -- by
+// enumerating every 7-card hand and propagating ranks back through the
+// same branch-chain state machine evalBoard/evalHand/EvalHand walk -- and
+// writes it to w in the same little-endian uint32 format LoadHandRanks
+// reads. This lets callers build a working table without shipping the
+// HandRanks.dat blob out-of-band.
+//
+// Unlike the original file, this doesn't collapse suit-isomorphic states,
+// which is the trick that keeps the real table to ~130MB: a table
+// generated here is correct but dramatically larger (about 5GB for the
+// real 52-card, 7-card table) and is not practical to generate on a
+// single machine in any reasonable time or memory budget. It's useful for
+// producing a genuinely working (if oversized) table for a reduced deck
+// -- see TestBuildTableMatchesRankFive, which runs this same code over 20
+// cards end-to-end -- not as a drop-in build step for the real 52-card
+// HandRanks.dat.
+func GenerateHandRanks(w io.Writer) error {
+	return writeHandRanks(w, buildTable(52, 7))
+}
+
+// buildTable runs the generator over a deck of numCards cards, ranking
+// every handLen-card hand reachable from it. GenerateHandRanks always
+// calls it with the real 52 and 7; it takes both as parameters so the
+// same branch-chain logic can be exercised on a small, fast instance in
+// tests, without the combinatorial blowup of the full deck.
+func buildTable(numCards uint32, handLen int) []uint32 {
+	g := &generator{
+		branches:  map[uint64]uint32{0: rootBranch},
+		table:     make([]uint32, rootBranch+branchSize, estimatedTableLen(numCards, handLen)),
+		rankCache: make(map[uint64]uint32),
+		numCards:  numCards,
+		handLen:   handLen,
+	}
+	g.build(0, 0)
+	return g.table
+}
+
+// estimatedTableLen is the number of uint32 slots buildTable will need:
+// the rootBranch leading slots plus one branchSize block for the root and
+// for every reachable 1..handLen-1-card state, so
+// the table slice can be preallocated instead of grown one branch-append
+// at a time.
+func estimatedTableLen(numCards uint32, handLen int) int {
+	branches := 1 // the root
+	for k := 1; k <= handLen-1; k++ {
+		branches += choose(int(numCards), k)
+	}
+	return rootBranch + branches*branchSize
+}
+
+type generator struct {
+	branches  map[uint64]uint32 // card-set bitmask -> branch id
+	table     []uint32
+	rankCache map[uint64]uint32 // handLen-card bitmask -> rank, since every
+	// distinct hand is reached once per card removed from it
+	numCards uint32
+	handLen  int
+}
+
+// branch returns the id of the branch for mask, allocating a fresh block
+// of branchSize slots the first time mask is seen.
+func (g *generator) branch(mask uint64) uint32 {
+	if id, ok := g.branches[mask]; ok {
+		return id
+	}
+	id := uint32(len(g.table))
+	g.table = append(g.table, make([]uint32, branchSize)...)
+	g.branches[mask] = id
+	return id
+}
+
+// build fills in every child of the branch for mask, which has depth
+// cards already chosen, recursing until all handLen card slots are
+// filled. At depth handLen-1, the final card's slot holds the hand's
+// rank directly, rather than another branch id, matching evalHand's
+// final hr[b+cards[1]] return.
+func (g *generator) build(mask uint64, depth int) {
+	id := g.branches[mask]
+	if depth >= 5 {
+		g.table[id] = bestOfN(cardsFromMask(mask, depth), 5)
+	}
+	for card := uint32(1); card <= g.numCards; card++ {
+		bit := uint64(1) << (card - 1)
+		if mask&bit != 0 {
+			continue
+		}
+		child := mask | bit
+		if depth == g.handLen-1 {
+			g.table[id+card] = g.terminalRank(child)
+			continue
+		}
+		_, seen := g.branches[child]
+		childID := g.branch(child)
+		g.table[id+card] = childID
+		if !seen {
+			g.build(child, depth+1)
+		}
+	}
+}
+
+// terminalRank returns the rank of the handLen-card hand in mask, caching
+// it: every such hand is otherwise reached once for each of its handLen
+// cards (via a different (handLen-1)-card parent branch), so without the
+// cache bestOfN would run handLen times more often than necessary.
+func (g *generator) terminalRank(mask uint64) uint32 {
+	if r, ok := g.rankCache[mask]; ok {
+		return r
+	}
+	r := bestOfN(cardsFromMask(mask, g.handLen), 5)
+	g.rankCache[mask] = r
+	return r
+}
+
+func writeHandRanks(w io.Writer, hr []uint32) error {
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, 4)
+	for _, v := range hr {
+		binary.LittleEndian.PutUint32(buf, v)
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func cardsFromMask(mask uint64, n int) []uint32 {
+	cards := make([]uint32, 0, n)
+	for card := uint32(1); len(cards) < n; card++ {
+		if mask&(1<<(card-1)) != 0 {
+			cards = append(cards, card)
+		}
+	}
+	return cards
+}
+
+// bestOfN returns the best k-card hand out of every k-card subset of
+// cards.
+func bestOfN(cards []uint32, k int) uint32 {
+	var best uint32
+	n := len(cards)
+	five := make([]uint32, 0, k)
+	for mask := 0; mask < 1<<uint(n); mask++ {
+		if popcount(mask) != k {
+			continue
+		}
+		five = five[:0]
+		for i, c := range cards {
+			if mask&(1<<uint(i)) != 0 {
+				five = append(five, c)
+			}
+		}
+		if r := rankFive(five); r > best {
+			best = r
+		}
+	}
+	return best
+}
+
+// rankFive scores a 5-card hand from scratch, independent of any Table,
+// packed as category<<12|subrank so it matches the convention SplitRank
+// already documents for the real table: bigger beats smaller, and the top
+// 12 bits alone separate straight flush down to high card.
+func rankFive(cards []uint32) uint32 {
+	counts := make(map[int]int, 5)
+	suitCounts := make(map[int]int, 4)
+	for _, c := range cards {
+		counts[cardRank(c)]++
+		suitCounts[cardSuit(c)]++
+	}
+	flush := false
+	for _, n := range suitCounts {
+		if n == 5 {
+			flush = true
+		}
+	}
+	ranksDesc := make([]int, 0, len(counts))
+	for r := range counts {
+		ranksDesc = append(ranksDesc, r)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ranksDesc)))
+	top, straight := straightTop(ranksDesc)
+
+	switch {
+	case straight && flush:
+		return pack(9, top)
+	case hasCount(counts, 4):
+		return pack(8, fourOfAKindRank(counts))
+	case hasCount(counts, 3) && hasCount(counts, 2):
+		return pack(7, fullHouseRank(counts))
+	case flush:
+		return pack(6, combIndex(ranksDesc))
+	case straight:
+		return pack(5, top)
+	case hasCount(counts, 3):
+		return pack(4, tripsRank(counts))
+	case pairCount(counts) == 2:
+		return pack(3, twoPairRank(counts))
+	case hasCount(counts, 2):
+		return pack(2, pairRank(counts))
+	default:
+		return pack(1, combIndex(ranksDesc))
+	}
+}
+
+func pack(category, subrank int) uint32 {
+	return uint32(category)<<12 | uint32(subrank)
+}
+
+// straightTop reports the top rank of the straight formed by the 5
+// distinct ranks in ranksDesc (sorted descending), treating A2345 as a
+// 5-high straight.
+func straightTop(ranksDesc []int) (int, bool) {
+	if len(ranksDesc) != 5 {
+		return 0, false
+	}
+	consecutive := true
+	for i := 0; i < 4; i++ {
+		if ranksDesc[i]-ranksDesc[i+1] != 1 {
+			consecutive = false
+			break
+		}
+	}
+	if consecutive {
+		return ranksDesc[0], true
+	}
+	wheel := []int{12, 3, 2, 1, 0} // A, 5, 4, 3, 2
+	for i, r := range wheel {
+		if ranksDesc[i] != r {
+			return 0, false
+		}
+	}
+	return 3, true // top card of the wheel is the 5
+}
+
+func hasCount(counts map[int]int, n int) bool {
+	for _, c := range counts {
+		if c == n {
+			return true
+		}
+	}
+	return false
+}
+
+func pairCount(counts map[int]int) int {
+	n := 0
+	for _, c := range counts {
+		if c == 2 {
+			n++
+		}
+	}
+	return n
+}
+
+// ranksWithCount returns, sorted descending, every rank in counts that
+// occurs exactly n times.
+func ranksWithCount(counts map[int]int, n int) []int {
+	var rs []int
+	for r, c := range counts {
+		if c == n {
+			rs = append(rs, r)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(rs)))
+	return rs
+}
+
+// remap maps each value in vals down into the dense 0..n-len(excl)-1
+// range left once every rank in excl is removed, by subtracting one for
+// each excluded rank below it. vals and excl must be disjoint.
+func remap(vals, excl []int) []int {
+	out := make([]int, len(vals))
+	for i, v := range vals {
+		shift := 0
+		for _, e := range excl {
+			if e < v {
+				shift++
+			}
+		}
+		out[i] = v - shift
+	}
+	return out
+}
+
+// choose is the binomial coefficient n-choose-k.
+func choose(n, k int) int {
+	if k < 0 || n < 0 || k > n {
+		return 0
+	}
+	num, den := 1, 1
+	for i := 0; i < k; i++ {
+		num *= n - i
+		den *= i + 1
+	}
+	return num / den
+}
+
+// combIndex returns the position, in colex order, of the distinct values
+// in vals (sorted descending) among all choose(13, len(vals)) subsets of
+// a 13-rank universe.
+func combIndex(vals []int) int {
+	idx := 0
+	for i, v := range vals {
+		idx += choose(v, len(vals)-i)
+	}
+	return idx
+}
+
+func fourOfAKindRank(counts map[int]int) int {
+	q := ranksWithCount(counts, 4)[0]
+	k := ranksWithCount(counts, 1)[0]
+	return q*12 + remap([]int{k}, []int{q})[0]
+}
+
+func fullHouseRank(counts map[int]int) int {
+	t := ranksWithCount(counts, 3)[0]
+	p := ranksWithCount(counts, 2)[0]
+	return t*12 + remap([]int{p}, []int{t})[0]
+}
+
+func tripsRank(counts map[int]int) int {
+	t := ranksWithCount(counts, 3)[0]
+	kickers := remap(ranksWithCount(counts, 1), []int{t})
+	return t*66 + combIndex(kickers)
+}
+
+func twoPairRank(counts map[int]int) int {
+	pairs := ranksWithCount(counts, 2) // length 2, descending
+	k := ranksWithCount(counts, 1)[0]
+	return combIndex(pairs)*11 + remap([]int{k}, pairs)[0]
+}
+
+func pairRank(counts map[int]int) int {
+	p := ranksWithCount(counts, 2)[0]
+	kickers := remap(ranksWithCount(counts, 1), []int{p})
+	return p*220 + combIndex(kickers)
+}
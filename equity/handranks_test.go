@@ -0,0 +1,64 @@
+package equity
+
+import (
+	"testing"
+
+	"poker/comb"
+)
+
+// TestBuildTableMatchesRankFive builds a table over a reduced, 20-card
+// deck -- small enough to run to completion -- and checks that walking its
+// branch chain reproduces rankFive's direct computation for every 5-card
+// hand. GenerateHandRanks runs the exact same code at the real 52-card,
+// 7-card scale, which is too large to verify this way; this is the
+// evidence that the branch-chain construction itself is correct.
+func TestBuildTableMatchesRankFive(t *testing.T) {
+	const numCards = 20
+	hr := buildTable(numCards, 5)
+
+	deck := make([]uint32, numCards)
+	for i := range deck {
+		deck[i] = uint32(i + 1)
+	}
+
+	hand := make([]uint32, 5)
+	c := comb.Generator(deck, 5)
+	for more := true; more; {
+		more = c(hand)
+		want := rankFive(hand)
+		got := walkChain(hr, hand)
+		if got != want {
+			t.Fatalf("chain rank for %v = %d, want %d (from rankFive)", hand, got, want)
+		}
+	}
+}
+
+func walkChain(hr []uint32, cards []uint32) uint32 {
+	v := hr[rootBranch+cards[0]]
+	for _, c := range cards[1:] {
+		v = hr[v+c]
+	}
+	return v
+}
+
+func TestRankFiveCategoryOrdering(t *testing.T) {
+	hands := [][]string{
+		{"Ac", "Kc", "Qc", "Jc", "Tc"}, // straight flush
+		{"Ac", "Ad", "Ah", "As", "2c"}, // four of a kind
+		{"Ac", "Ad", "Ah", "2c", "2d"}, // full house
+		{"2c", "5c", "9c", "Jc", "Ac"}, // flush
+		{"2c", "3d", "4h", "5s", "6c"}, // straight
+		{"Ac", "Ad", "Ah", "2c", "3d"}, // three of a kind
+		{"Ac", "Ad", "2c", "2d", "3h"}, // two pair
+		{"Ac", "Ad", "2c", "3d", "4h"}, // one pair
+		{"Ac", "Kd", "2c", "5d", "9h"}, // high card
+	}
+	prev := uint32(1 << 30)
+	for _, h := range hands {
+		r := rankFive(cardsToInts(h))
+		if r >= prev {
+			t.Fatalf("rankFive(%v) = %d, want less than the previous, stronger hand's %d", h, r, prev)
+		}
+		prev = r
+	}
+}
@@ -0,0 +1,61 @@
+package equity
+
+import "sort"
+
+// lowValue maps a card's rank index (into the ranks constant) to its
+// ace-to-five lowball value: A is the lowest card (1), 2 through K rank
+// upward in the usual order.
+func lowValue(rankIdx int) int {
+	if rankIdx == len(ranks)-1 { // 'A' is the last rank
+		return 1
+	}
+	return rankIdx + 2
+}
+
+func cardRank(c uint32) int { return int((c - 1) / 4) }
+func cardSuit(c uint32) int { return int((c - 1) % 4) }
+
+// lowballRank scores a 5-card hand for ace-to-five lowball, where straights
+// and flushes don't count against the hand. Bigger is better, mirroring
+// the convention used by the 2+2 hi ranks, so hi and lo ranks can share the
+// same max-wins comparison in evalHands.
+//
+// If eightOrBetter is true, the hand only qualifies (ok == true) when it
+// has five distinct ranks of eight or less. Otherwise any 5 cards qualify,
+// including paired hands, which simply rank worse than unpaired ones.
+func lowballRank(cards []uint32, eightOrBetter bool) (rank uint32, ok bool) {
+	counts := make(map[int]int, 5)
+	for _, c := range cards {
+		v := lowValue(cardRank(c))
+		if eightOrBetter && v > 8 {
+			return 0, false
+		}
+		counts[v]++
+	}
+	if eightOrBetter && len(counts) != 5 {
+		return 0, false
+	}
+
+	values := make([]int, 0, len(counts))
+	for v := range counts {
+		values = append(values, v)
+	}
+	sort.Ints(values)
+
+	// Fewer distinct ranks (more pairing) is worse. This ignores the
+	// full-house-vs-trips-kicker subtleties of rare paired Razz hands,
+	// which is fine for 8-or-better qualifiers (which never pair) and
+	// left as a simplification for plain Razz.
+	severity := 5 - len(values)
+	total := severity
+	for i := len(values) - 1; i >= 0; i-- {
+		total = total*14 + values[i]
+	}
+	// Pad to five value digits so severity always lands in the same place.
+	for i := len(values); i < 5; i++ {
+		total *= 14
+	}
+	return lowballMax - uint32(total), true
+}
+
+const lowballMax = 1 << 28
@@ -0,0 +1,209 @@
+package equity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Range is the set of concrete two-card hole-card combos produced by
+// ParseRange, e.g. the 38 combos making up "QQ+,AQs+,AK".
+type Range struct {
+	Hands [][]uint32
+}
+
+// ParseRange parses a comma-separated list of PokerStove-style range
+// tokens into the concrete two-card combos they represent. Supported
+// token forms, same syntax as the package doc comment above:
+//
+//	AA, AKo, AKs, 54   a single HandDist
+//	77+, AJs+, AJo+    pair or kicker "plus" ranges
+//	T9o-65o, A5s-A2s   dash ranges between two tokens of the same shape
+//	AhKh               a fully specified two-card combo
+func ParseRange(s string) (*Range, error) {
+	r := &Range{}
+	seen := make(map[[2]uint32]bool)
+	for _, tok := range strings.Split(s, ",") {
+		tok = strings.TrimSpace(tok)
+		if tok == "" {
+			continue
+		}
+		hands, err := parseToken(tok)
+		if err != nil {
+			return nil, fmt.Errorf("equity: invalid range token %q: %v", tok, err)
+		}
+		for _, h := range hands {
+			key := [2]uint32{h[0], h[1]}
+			if key[0] > key[1] {
+				key[0], key[1] = key[1], key[0]
+			}
+			if !seen[key] {
+				seen[key] = true
+				r.Hands = append(r.Hands, h)
+			}
+		}
+	}
+	return r, nil
+}
+
+func parseToken(tok string) ([][]uint32, error) {
+	switch {
+	case strings.Contains(tok, "-"):
+		return parseDashRange(tok)
+	case strings.HasSuffix(tok, "+"):
+		return parsePlus(strings.TrimSuffix(tok, "+"))
+	case isCardCombo(tok):
+		if rankIndex(tok[0]) < 0 || rankIndex(tok[2]) < 0 {
+			return nil, fmt.Errorf("unknown rank in %q", tok)
+		}
+		return [][]uint32{cardsToInts([]string{tok[0:2], tok[2:4]})}, nil
+	default:
+		if err := validateHandDist(tok); err != nil {
+			return nil, err
+		}
+		return (&HandDist{tok}).Ints(), nil
+	}
+}
+
+// isCardCombo reports whether tok fully specifies two cards, e.g. "AhKh",
+// rather than a HandDist category such as "AKs".
+func isCardCombo(tok string) bool {
+	if len(tok) != 4 {
+		return false
+	}
+	return strings.IndexByte(suits, tok[1]) >= 0 && strings.IndexByte(suits, tok[3]) >= 0
+}
+
+// validateHandDist reports an error if tok isn't a well-formed bare
+// HandDist token: two rank characters (e.g. "AK", "77"), optionally
+// followed by an 's' or 'o' suited/offsuit qualifier.
+func validateHandDist(tok string) error {
+	if len(tok) != 2 && len(tok) != 3 {
+		return fmt.Errorf("unrecognized range token %q", tok)
+	}
+	if rankIndex(tok[0]) < 0 || rankIndex(tok[1]) < 0 {
+		return fmt.Errorf("unknown rank in %q", tok)
+	}
+	if len(tok) == 3 && tok[2] != 'o' && tok[2] != 's' {
+		return fmt.Errorf("unrecognized suit qualifier in %q", tok)
+	}
+	return nil
+}
+
+func rankIndex(r byte) int {
+	return strings.IndexByte(ranks, r)
+}
+
+// parsePlus expands "77+" into 77..AA and "AJs+"/"AJo+" into AJs..AKs.
+func parsePlus(dist string) ([][]uint32, error) {
+	switch len(dist) {
+	case 2:
+		if dist[0] != dist[1] {
+			return nil, fmt.Errorf("%q is not a pair", dist)
+		}
+		lo := rankIndex(dist[0])
+		if lo < 0 {
+			return nil, fmt.Errorf("unknown rank in %q", dist)
+		}
+		var hands [][]uint32
+		for i := lo; i < len(ranks); i++ {
+			hands = append(hands, (&HandDist{string([]byte{ranks[i], ranks[i]})}).Ints()...)
+		}
+		return hands, nil
+	case 3:
+		hi, lo, suf := dist[0], dist[1], dist[2]
+		hiIdx, loIdx := rankIndex(hi), rankIndex(lo)
+		if hiIdx < 0 || loIdx < 0 || hiIdx <= loIdx {
+			return nil, fmt.Errorf("bad kicker range %q", dist)
+		}
+		var hands [][]uint32
+		for i := loIdx; i < hiIdx; i++ {
+			hands = append(hands, (&HandDist{string([]byte{hi, ranks[i], suf})}).Ints()...)
+		}
+		return hands, nil
+	}
+	return nil, fmt.Errorf("unrecognized range %q+", dist)
+}
+
+// parseDashRange expands a dash range like "T9o-65o" (walking the
+// connectors) or "A5s-A2s" (walking the kicker, top rank held fixed).
+// Both ends of the dash must share the same gap between their two ranks
+// (for a connector walk) or the same top rank (for a kicker walk).
+func parseDashRange(tok string) ([][]uint32, error) {
+	parts := strings.SplitN(tok, "-", 2)
+	hi, lo := parts[0], parts[1]
+	if len(hi) != len(lo) || len(hi) < 2 {
+		return nil, fmt.Errorf("mismatched range bounds %q", tok)
+	}
+	hiR1, hiR2 := rankIndex(hi[0]), rankIndex(hi[1])
+	loR1, loR2 := rankIndex(lo[0]), rankIndex(lo[1])
+	if hiR1 < 0 || hiR2 < 0 || loR1 < 0 || loR2 < 0 {
+		return nil, fmt.Errorf("unknown rank in %q", tok)
+	}
+	var suf byte
+	if len(hi) == 3 {
+		if hi[2] != lo[2] {
+			return nil, fmt.Errorf("mismatched suit qualifiers in %q", tok)
+		}
+		suf = hi[2]
+	}
+	var hands [][]uint32
+	switch {
+	case hiR1 == loR1:
+		// Kicker range, e.g. A5s-A2s: top rank fixed, kicker walks.
+		top, bot := hiR2, loR2
+		if bot > top {
+			top, bot = bot, top
+		}
+		for i := bot; i <= top; i++ {
+			hands = append(hands, dashHand(hi[0], ranks[i], suf)...)
+		}
+	case hiR1-hiR2 == loR1-loR2:
+		// Connector range, e.g. T9o-65o: gap between the two ranks fixed.
+		gap := hiR1 - hiR2
+		top, bot := hiR1, loR1
+		if bot > top {
+			top, bot = bot, top
+		}
+		for i := bot; i <= top; i++ {
+			if j := i - gap; j >= 0 {
+				hands = append(hands, dashHand(ranks[i], ranks[j], suf)...)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%q is not a connector or kicker range", tok)
+	}
+	return hands, nil
+}
+
+func dashHand(r1, r2, suf byte) [][]uint32 {
+	dist := []byte{r1, r2}
+	if suf != 0 {
+		dist = append(dist, suf)
+	}
+	return (&HandDist{string(dist)}).Ints()
+}
+
+// Remove returns a new Range with every combo that shares a card with
+// seen removed, leaving each surviving combo weighted by however many of
+// its card pairs remain in the deck.
+func (r *Range) Remove(seen []uint32) *Range {
+	out := &Range{}
+	for _, h := range r.Hands {
+		blocked := false
+		for _, c := range h {
+			for _, s := range seen {
+				if c == s {
+					blocked = true
+					break
+				}
+			}
+			if blocked {
+				break
+			}
+		}
+		if !blocked {
+			out.Hands = append(out.Hands, h)
+		}
+	}
+	return out
+}
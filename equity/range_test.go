@@ -0,0 +1,39 @@
+package equity
+
+import "testing"
+
+func TestParseRangeComboCounts(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int
+	}{
+		{"AA", 6},
+		{"AKs", 4},
+		{"AKo", 12},
+		{"AK", 16},      // regression: used to silently come back as 6 pocket-Ace combos
+		{"54", 16},
+		{"77+", 48},     // 77..AA, 8 ranks * 6 combos each
+		{"AJs+", 12},    // AJs, AQs, AKs
+		{"65o-T9o", 60}, // 65o,76o,87o,98o,T9o, 12 offsuit combos each
+		{"AhKh,7h7d", 2},
+	}
+	for _, c := range cases {
+		r, err := ParseRange(c.in)
+		if err != nil {
+			t.Fatalf("ParseRange(%q): %v", c.in, err)
+		}
+		if got := len(r.Hands); got != c.want {
+			t.Errorf("ParseRange(%q) = %d combos, want %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseRangeRejectsMalformedTokens(t *testing.T) {
+	// These used to either panic (too short to index) or return a nil
+	// error with a bogus, zero-valued card baked into the hand.
+	for _, in := range []string{"A", "XYs", "A1s", "AhXh", "Ah1h"} {
+		if _, err := ParseRange(in); err == nil {
+			t.Errorf("ParseRange(%q) = nil error, want one", in)
+		}
+	}
+}